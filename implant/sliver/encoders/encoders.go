@@ -19,12 +19,17 @@ package encoders
 */
 
 import (
+	"bytes"
 	"crypto/rand"
 	"embed"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	insecureRand "math/rand"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	// {{if .Config.Debug}}
 	"log"
@@ -50,6 +55,14 @@ var (
 	GzipEncoderID    = uint64(49)
 	HexEncoderID     = uint64(92)
 	PNGEncoderID     = uint64(22)
+	MultiEncoderID   = uint64(157)
+)
+
+var (
+	// ErrInvalidNonce - The nonce could not have been generated by RandomEncoder
+	ErrInvalidNonce = errors.New("invalid encoder nonce")
+	// ErrUnknownEncoderID - The nonce is valid but no encoder is loaded for its ID
+	ErrUnknownEncoderID = errors.New("unknown encoder id")
 )
 
 func init() {
@@ -58,7 +71,6 @@ func init() {
 		// {{if .Config.Debug}}
 		log.Printf("Failed to load WASM encoders: %v", err)
 		// {{end}}
-		return
 	}
 	err = loadEnglishDictionaryFromAssets()
 	if err != nil {
@@ -80,15 +92,19 @@ var (
 	English = EnglishEncoder{}
 	Gzip    = GzipEncoder{}
 	PNG     = PNGEncoder{}
+	Multi   = NewMultiEncoder(Gzip, Base64, PNG)
 
 	// {{if .Config.Debug}}
 	Nop = NoEncoder{}
 	// {{end}}
 )
 
-// EncoderMap - Maps EncoderIDs to Encoders
+// EncoderMap - Maps EncoderIDs to Encoders, guarded by encoderMapMutex
 var EncoderMap = map[uint64]Encoder{}
 
+// encoderMapMutex - Guards EncoderMap and NativeEncoderMap
+var encoderMapMutex = &sync.RWMutex{}
+
 // EncoderMap - Maps EncoderIDs to Encoders
 var NativeEncoderMap = map[uint64]Encoder{
 	Base64EncoderID:  Base64,
@@ -96,6 +112,7 @@ var NativeEncoderMap = map[uint64]Encoder{
 	EnglishEncoderID: English,
 	GzipEncoderID:    Gzip,
 	PNGEncoderID:     PNG,
+	MultiEncoderID:   Multi,
 
 	// {{if .Config.Debug}}
 	0: NoEncoder{},
@@ -110,36 +127,230 @@ type Encoder interface {
 
 // EncoderFromNonce - Convert a nonce into an encoder
 func EncoderFromNonce(nonce uint64) (uint64, Encoder, error) {
+	if MaxN <= nonce/EncoderModulus {
+		return 0, nil, ErrInvalidNonce
+	}
 	encoderID := nonce % EncoderModulus
 	if encoderID == 0 {
 		return 0, new(NoEncoder), nil
 	}
+	encoderMapMutex.RLock()
+	defer encoderMapMutex.RUnlock()
+	if encoder, ok := NativeEncoderMap[encoderID]; ok {
+		return encoderID, encoder, nil
+	}
 	if encoder, ok := EncoderMap[encoderID]; ok {
 		return encoderID, encoder, nil
 	}
-	return 0, nil, errors.New("invalid encoder nonce")
+	return 0, nil, fmt.Errorf("%w: %d", ErrUnknownEncoderID, encoderID)
+}
+
+// RegisterEncoder - Add an encoder to the EncoderMap at runtime, returns an error if
+// the ID is already in use by another encoder and overwrite is false. Native encoder
+// IDs are reserved and can never be overwritten
+func RegisterEncoder(encoderID uint64, encoder Encoder, overwrite bool) error {
+	if encoderID == 0 || EncoderModulus <= encoderID {
+		return fmt.Errorf("invalid encoder id: %d", encoderID)
+	}
+	if encoder == nil {
+		return errors.New("encoder cannot be nil")
+	}
+	encoderMapMutex.Lock()
+	defer encoderMapMutex.Unlock()
+	if _, isNative := NativeEncoderMap[encoderID]; isNative {
+		return fmt.Errorf("reserved encoder id: %d", encoderID)
+	}
+	if _, inUse := EncoderMap[encoderID]; inUse && !overwrite {
+		return fmt.Errorf("duplicate encoder id: %d", encoderID)
+	}
+	EncoderMap[encoderID] = encoder
+	return nil
+}
+
+// UnregisterEncoder - Remove an encoder from the EncoderMap
+func UnregisterEncoder(encoderID uint64) {
+	encoderMapMutex.Lock()
+	defer encoderMapMutex.Unlock()
+	delete(EncoderMap, encoderID)
+}
+
+var (
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	gzipMagic = []byte("\x1f\x8b")
+)
+
+// minDetectTextLen - Shorter text is too easily mistaken for an encoding
+const minDetectTextLen = 16
+
+// DetectEncoder - Sniff the data to determine which native encoder produced it,
+// useful when there's no nonce available. Returns false for plain or ambiguous data.
+// Base58 and Base64 are never detected since their alphabets accept most plain
+// alphanumeric text, and Hex is only detected when it isn't also valid Base32,
+// which depends on the length of the encoded data
+func DetectEncoder(data []byte) (uint64, Encoder, bool) {
+	if len(data) == 0 {
+		return 0, nil, false
+	}
+	if bytes.HasPrefix(data, pngMagic) {
+		if isMultiEncoded(data) {
+			return MultiEncoderID, Multi, true
+		}
+		return PNGEncoderID, PNG, true
+	}
+	if bytes.HasPrefix(data, gzipMagic) {
+		return GzipEncoderID, Gzip, true
+	}
+	if isEnglishText(data) {
+		return EnglishEncoderID, English, true
+	}
+
+	if len(data) < minDetectTextLen {
+		return 0, nil, false
+	}
+
+	// The text encoders' alphabets overlap so a match requires the data to survive
+	// a round trip, and exactly one of the encoders to match
+	textEncoders := []struct {
+		id      uint64
+		encoder Encoder
+	}{
+		{HexEncoderID, Hex},
+		{Base32EncoderID, Base32},
+	}
+	matches := 0
+	var matchID uint64
+	var matchEncoder Encoder
+	for _, text := range textEncoders {
+		decoded, err := text.encoder.Decode(data)
+		if err != nil || len(decoded) == 0 {
+			continue
+		}
+		encoded, err := text.encoder.Encode(decoded)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(encoded, data) {
+			matches++
+			matchID, matchEncoder = text.id, text.encoder
+		}
+	}
+	if matches != 1 {
+		return 0, nil, false
+	}
+	return matchID, matchEncoder, true
+}
+
+// isMultiEncoded - Peels the PNG and Base64 stages of the Multi chain and checks
+// for the Gzip magic of its first stage
+func isMultiEncoded(data []byte) bool {
+	inner, err := PNG.Decode(data)
+	if err != nil {
+		return false
+	}
+	inner, err = Base64.Decode(inner)
+	if err != nil {
+		return false
+	}
+	return bytes.HasPrefix(inner, gzipMagic)
+}
+
+// isEnglishText - English encoded data is dictionary words separated by spaces,
+// a single word is ambiguous with the other text encoders
+func isEnglishText(data []byte) bool {
+	if !English.Ready() || !bytes.Contains(data, []byte(" ")) {
+		return false
+	}
+	for _, word := range strings.Split(string(data), " ") {
+		if !inEnglishDictionary(word) {
+			return false
+		}
+	}
+	return true
+}
+
+// MultiEncoder - Chains an ordered list of encoders, Encode is applied
+// left-to-right and Decode is applied right-to-left
+type MultiEncoder struct {
+	encoders []Encoder
+}
+
+// NewMultiEncoder - Create a MultiEncoder from an ordered list of encoders,
+// an empty chain behaves like the NoEncoder
+func NewMultiEncoder(encoders ...Encoder) *MultiEncoder {
+	return &MultiEncoder{encoders: encoders}
+}
+
+// Encode - Apply each encoder in order
+func (m *MultiEncoder) Encode(data []byte) ([]byte, error) {
+	var err error
+	for index, encoder := range m.encoders {
+		data, err = encoder.Encode(data)
+		if err != nil {
+			return nil, fmt.Errorf("multi encoder stage %d: %w", index, err)
+		}
+	}
+	return data, nil
+}
+
+// Decode - Apply each decoder in reverse order
+func (m *MultiEncoder) Decode(data []byte) ([]byte, error) {
+	var err error
+	for index := len(m.encoders) - 1; 0 <= index; index-- {
+		data, err = m.encoders[index].Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("multi decoder stage %d: %w", index, err)
+		}
+	}
+	return data, nil
 }
 
 func getMaxEncoderSize() int {
 	return 16 * 1024 * 1024 // 16MB
 }
 
+var (
+	// defaultRand is not safe for concurrent use on its own
+	defaultRand      = insecureRand.New(insecureRand.NewSource(time.Now().UnixNano()))
+	defaultRandMutex = &sync.Mutex{}
+)
+
 // RandomEncoder - Get a random nonce identifier and a matching encoder
 func RandomEncoder(size int) (uint64, Encoder) {
+	defaultRandMutex.Lock()
+	defer defaultRandMutex.Unlock()
+	return randomEncoder(defaultRand, randomUint64, size)
+}
+
+// RandomEncoderWithRand - Get a random nonce identifier and a matching encoder, both
+// are derived from r so the same seed always yields the same nonce and encoder
+func RandomEncoderWithRand(r *insecureRand.Rand, size int) (uint64, Encoder) {
+	return randomEncoder(r, func(max uint64) uint64 {
+		return r.Uint64() % max
+	}, size)
+}
+
+func randomEncoder(r *insecureRand.Rand, nonceN func(uint64) uint64, size int) (uint64, Encoder) {
+	encoderMapMutex.RLock()
+	defer encoderMapMutex.RUnlock()
 	if size < getMaxEncoderSize() && len(EncoderMap) > 0 {
-		return randomEncoderFromMap(EncoderMap) // Small message, use any encoder
+		return randomEncoderFromMap(r, nonceN, EncoderMap) // Small message, use any encoder
 	} else {
-		return randomEncoderFromMap(NativeEncoderMap) // Large message, use native encoders
+		return randomEncoderFromMap(r, nonceN, NativeEncoderMap) // Large message, use native encoders
 	}
 }
 
-func randomEncoderFromMap(encoderMap map[uint64]Encoder) (uint64, Encoder) {
+func randomEncoderFromMap(r *insecureRand.Rand, nonceN func(uint64) uint64, encoderMap map[uint64]Encoder) (uint64, Encoder) {
 	keys := make([]uint64, 0, len(encoderMap))
 	for k := range encoderMap {
+		if k == EnglishEncoderID && !English.Ready() {
+			continue // Dictionary failed to load
+		}
 		keys = append(keys, k)
 	}
-	encoderID := keys[insecureRand.Intn(len(keys))]
-	nonce := (randomUint64(MaxN) * EncoderModulus) + encoderID
+	// Map iteration order is random, so sort the keys for a deterministic selection
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	encoderID := keys[r.Intn(len(keys))]
+	nonce := (nonceN(MaxN) * EncoderModulus) + encoderID
 	return nonce, encoderMap[encoderID]
 }
 
@@ -193,7 +404,13 @@ func loadWasmEncodersFromAssets() error {
 		if err != nil {
 			return err
 		}
+		encoderMapMutex.Lock()
+		if _, ok := NativeEncoderMap[wasmEncoderID]; ok {
+			encoderMapMutex.Unlock()
+			return fmt.Errorf("duplicate encoder id: %d", wasmEncoderID)
+		}
 		EncoderMap[wasmEncoderID] = trafficEncoder
+		encoderMapMutex.Unlock()
 		// {{if .Config.Debug}}
 		log.Printf("loading %s (id: %d, bytes: %d)", wasmEncoderModuleName, wasmEncoderID, len(wasmEncoderData))
 		// {{end}}
@@ -201,9 +418,11 @@ func loadWasmEncodersFromAssets() error {
 	// {{if .Config.Debug}}
 	log.Printf("completed loading traffic encoders")
 	log.Printf("current encoder map:")
+	encoderMapMutex.RLock()
 	for encoderID, encoder := range EncoderMap {
 		log.Printf("encoder %d -> %#v", encoderID, encoder)
 	}
+	encoderMapMutex.RUnlock()
 	//	{{end}}
 
 	// *** {{end}} ***
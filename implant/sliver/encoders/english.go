@@ -19,11 +19,24 @@ package encoders
 */
 
 import (
+	"errors"
+	"math"
 	insecureRand "math/rand"
 	"strings"
+	"sync"
 )
 
-var dictionary map[int][]string
+// defaultEnglishWordLen - Conservative estimate of the average word length used
+// when the dictionary has not been loaded
+const defaultEnglishWordLen = 16
+
+// ErrDictionaryNotLoaded - The english dictionary failed to load from the implant's assets
+var ErrDictionaryNotLoaded = errors.New("english dictionary not loaded")
+
+var (
+	dictionary     map[int][]string
+	dictionaryOnce sync.Once
+)
 
 var rawEnglishDictionary []string
 
@@ -34,14 +47,19 @@ func getEnglishDictionary() []string {
 // English Encoder - An ASCIIEncoder for binary to english text
 type EnglishEncoder struct{}
 
+// Ready - Returns true if the dictionary is loaded and the encoder can be used
+func (e EnglishEncoder) Ready() bool {
+	return 0 < len(getEnglishDictionary())
+}
+
 // Encode - Binary => English
 func (e EnglishEncoder) Encode(data []byte) ([]byte, error) {
-	if dictionary == nil {
-		buildDictionary()
+	if !e.Ready() {
+		return nil, ErrDictionaryNotLoaded
 	}
 	words := []string{}
 	for _, b := range data {
-		possibleWords := dictionary[int(b)]
+		possibleWords := getDictionary()[int(b)]
 		index := insecureRand.Intn(len(possibleWords))
 		words = append(words, possibleWords[index])
 	}
@@ -50,6 +68,9 @@ func (e EnglishEncoder) Encode(data []byte) ([]byte, error) {
 
 // Decode - English => Binary
 func (e EnglishEncoder) Decode(words []byte) ([]byte, error) {
+	if !e.Ready() {
+		return nil, ErrDictionaryNotLoaded
+	}
 	wordList := strings.Split(string(words), " ")
 	data := []byte{}
 	for _, word := range wordList {
@@ -63,6 +84,47 @@ func (e EnglishEncoder) Decode(words []byte) ([]byte, error) {
 	return data, nil
 }
 
+// EncodedLen - Approximate length of the encoded form of n bytes of input, based
+// on the average word length of the loaded dictionary
+func (e EnglishEncoder) EncodedLen(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return n*averageEnglishWordLen() + (n - 1) // One word per byte, plus separators
+}
+
+func averageEnglishWordLen() int {
+	words := getEnglishDictionary()
+	total, count := 0, 0
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if len(word) == 0 {
+			continue
+		}
+		total += len(word)
+		count++
+	}
+	if count == 0 {
+		return defaultEnglishWordLen
+	}
+	return int(math.Ceil(float64(total) / float64(count)))
+}
+
+// inEnglishDictionary - Returns true if the word is in the loaded dictionary
+func inEnglishDictionary(word string) bool {
+	for _, entry := range getDictionary()[sumWord(word)] {
+		if entry == word {
+			return true
+		}
+	}
+	return false
+}
+
+func getDictionary() map[int][]string {
+	dictionaryOnce.Do(buildDictionary)
+	return dictionary
+}
+
 func buildDictionary() {
 	dictionary = map[int][]string{}
 	for _, word := range getEnglishDictionary() {
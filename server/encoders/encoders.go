@@ -49,6 +49,7 @@ var (
 	Gzip    = util.Gzip{}
 	PNG     = util.PNGEncoder{}
 	Nop     = util.NoEncoder{}
+	Multi   = util.NewMultiEncoder(Gzip, Base64, PNG)
 )
 
 func init() {
@@ -70,6 +71,7 @@ var EncoderMap = map[uint64]util.Encoder{
 	util.EnglishEncoderID: English,
 	util.GzipEncoderID:    Gzip,
 	util.PNGEncoderID:     PNG,
+	util.MultiEncoderID:   Multi,
 }
 
 // TrafficEncoderMap - Keeps track of the loaded traffic encoders (i.e., wasm-based encoder functions)
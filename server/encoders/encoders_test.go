@@ -228,6 +228,32 @@ func TestCompatibilityPNG(t *testing.T) {
 	}
 }
 
+func TestCompatibilityMulti(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		sample := randomDataRandomSize(sampleSizeMax)
+		output, _ := Multi.Encode(sample)
+		data, err := implantEncoders.Multi.Decode(output)
+		if err != nil {
+			t.Error("Failed to encode/decode sample data into multi")
+			return
+		}
+		if !bytes.Equal(sample, data) {
+			t.Errorf("sample does not match returned\n%#v != %#v", sample, data)
+		}
+
+		sample2 := randomDataRandomSize(sampleSizeMax)
+		output, _ = implantEncoders.Multi.Encode(sample2)
+		data, err = Multi.Decode(output)
+		if err != nil {
+			t.Error("Failed to encode/decode sample data into multi")
+			return
+		}
+		if !bytes.Equal(sample2, data) {
+			t.Errorf("sample2 does not match returned\n%#v != %#v", sample2, data)
+		}
+	}
+}
+
 func getTestEnglishDictionary() []string {
 	return []string{
 
@@ -35,6 +35,7 @@ const (
 	GzipEncoderID    = uint64(49)
 	HexEncoderID     = uint64(92)
 	PNGEncoderID     = uint64(22)
+	MultiEncoderID   = uint64(157)
 	NoEncoderID      = uint64(0)
 )
 
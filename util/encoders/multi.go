@@ -0,0 +1,57 @@
+package encoders
+
+/*
+	Sliver Implant Framework
+	Copyright (C) 2019  Bishop Fox
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "fmt"
+
+// MultiEncoder - Chains an ordered list of encoders, Encode is applied
+// left-to-right and Decode is applied right-to-left
+type MultiEncoder struct {
+	encoders []Encoder
+}
+
+// NewMultiEncoder - Create a MultiEncoder from an ordered list of encoders,
+// an empty chain behaves like the NoEncoder
+func NewMultiEncoder(encoders ...Encoder) *MultiEncoder {
+	return &MultiEncoder{encoders: encoders}
+}
+
+// Encode - Apply each encoder in order
+func (m *MultiEncoder) Encode(data []byte) ([]byte, error) {
+	var err error
+	for index, encoder := range m.encoders {
+		data, err = encoder.Encode(data)
+		if err != nil {
+			return nil, fmt.Errorf("multi encoder stage %d: %w", index, err)
+		}
+	}
+	return data, nil
+}
+
+// Decode - Apply each decoder in reverse order
+func (m *MultiEncoder) Decode(data []byte) ([]byte, error) {
+	var err error
+	for index := len(m.encoders) - 1; 0 <= index; index-- {
+		data, err = m.encoders[index].Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("multi decoder stage %d: %w", index, err)
+		}
+	}
+	return data, nil
+}